@@ -0,0 +1,76 @@
+package types
+
+// ImageInfo describes an image stored by the daemon. Only the fields
+// consumed by daemon/mgr are reproduced here.
+type ImageInfo struct {
+	Architecture string
+	Config       *ImageInfoConfig
+	CreatedAt    string
+	ID           string
+	Os           string
+	// Platform carries the OS/architecture/variant of this particular
+	// image, letting callers tell apart the per-platform images a
+	// manifest-list pull registers under synthetic references. Os and
+	// Architecture above are kept for backwards compatibility.
+	Platform    *Platform
+	RepoDigests []string
+	RepoTags    []string
+	RootFS      *ImageInfoRootFS
+	Size        int64
+}
+
+// ImageInfoConfig is the subset of the image's OCI config surfaced to API
+// callers.
+type ImageInfoConfig struct {
+	Cmd        []string
+	Entrypoint []string
+	Env        []string
+	Labels     map[string]string
+	User       string
+	WorkingDir string
+}
+
+// ImageInfoRootFS describes an image's layer stack.
+type ImageInfoRootFS struct {
+	Type   string
+	Layers []string
+}
+
+// Platform identifies the OS/architecture/variant an image was built for,
+// matching the terms used in an OCI manifest list / Docker manifest list.
+type Platform struct {
+	OS           string
+	Architecture string
+	Variant      string
+}
+
+// AuthConfig carries registry credentials.
+type AuthConfig struct {
+	Username string
+	Password string
+}
+
+// ImageDeleteResponseItem records one reference removed by RemoveImage or
+// PruneImages.
+type ImageDeleteResponseItem struct {
+	Deleted  string
+	Untagged string
+}
+
+// HistoryResponseItem is one entry of ImageHistory's per-layer provenance.
+type HistoryResponseItem struct {
+	Created    string
+	CreatedBy  string
+	Comment    string
+	EmptyLayer bool
+	Size       int64
+}
+
+// SearchResultItem is one entry returned by SearchImages.
+type SearchResultItem struct {
+	Name        string
+	Description string
+	StarCount   int64
+	IsOfficial  bool
+	IsAutomated bool
+}
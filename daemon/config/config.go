@@ -0,0 +1,64 @@
+package config
+
+import "time"
+
+// Config holds daemon-wide configuration. Only the fields consumed by
+// daemon/mgr are reproduced here.
+type Config struct {
+	// DefaultRegistry is the default registry of daemon.
+	// When users do not specify image repo in image name,
+	// daemon will automatically pull images with DefaultRegistry and DefaultNamespace.
+	DefaultRegistry string
+
+	// DefaultRegistryNS is the default namespace used in DefaultRegistry.
+	DefaultRegistryNS string
+
+	// RegistryPullRetry configures ImageManager.PullImage's
+	// retry-with-backoff behavior against transient registry failures.
+	// The zero value falls back to retry.DefaultPolicy().
+	RegistryPullRetry RegistryPullRetryConfig
+
+	// RegistryTLS configures the TLS client used to reach registries for
+	// SearchImages (and, in future, other direct-HTTP registry calls).
+	// The zero value uses the system's default trust store.
+	RegistryTLS RegistryTLSConfig
+}
+
+// RegistryTLSConfig mirrors the per-registry TLS settings the daemon
+// already accepts on the command line (--tlscacert/--tlscert/--tlskey/
+// --tlsverify), reused here so SearchImages can reach private/self-signed
+// registries.
+type RegistryTLSConfig struct {
+	// CAFile, if set, is used instead of the system trust store to verify
+	// the registry's certificate.
+	CAFile string
+
+	// CertFile/KeyFile, if set, are presented as a client certificate.
+	CertFile string
+	KeyFile  string
+
+	// InsecureSkipVerify disables certificate verification entirely, for
+	// registries reachable only over a self-signed or unverifiable cert.
+	InsecureSkipVerify bool
+}
+
+// RegistryPullRetryConfig is the user-facing knobs for pkg/retry.Policy, as
+// plumbed through the daemon's config file/flags.
+type RegistryPullRetryConfig struct {
+	// MaxAttempts is the total number of attempts, including the first
+	// one. 0 means "use the default policy".
+	MaxAttempts int
+
+	// InitialDelay is the delay before the first retry.
+	InitialDelay time.Duration
+
+	// Factor multiplies the delay after every attempt.
+	Factor float64
+
+	// MaxDelay caps the computed delay, regardless of Factor.
+	MaxDelay time.Duration
+
+	// Jitter adds random variance to each computed delay so concurrent
+	// retries against the same registry don't thunder in lockstep.
+	Jitter bool
+}
@@ -2,16 +2,23 @@ package mgr
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/alibaba/pouch/apis/types"
 	"github.com/alibaba/pouch/ctrd"
 	"github.com/alibaba/pouch/daemon/config"
 	"github.com/alibaba/pouch/pkg/errtypes"
+	"github.com/alibaba/pouch/pkg/filters"
 	"github.com/alibaba/pouch/pkg/jsonstream"
 	"github.com/alibaba/pouch/pkg/reference"
+	"github.com/alibaba/pouch/pkg/retry"
 	"github.com/alibaba/pouch/pkg/utils"
 
 	"github.com/containerd/containerd"
@@ -26,8 +33,8 @@ type ImageMgr interface {
 	// PullImage pulls images from specified registry.
 	PullImage(ctx context.Context, ref string, authConfig *types.AuthConfig, out io.Writer) error
 
-	// ListImages lists images stored by containerd.
-	ListImages(ctx context.Context, filter ...string) ([]types.ImageInfo, error)
+	// ListImages lists images stored by containerd, narrowed by filter.
+	ListImages(ctx context.Context, filter filters.Args) ([]types.ImageInfo, error)
 
 	// Search Images from specified registry.
 	SearchImages(ctx context.Context, name string, registry string) ([]types.SearchResultItem, error)
@@ -38,6 +45,27 @@ type ImageMgr interface {
 	// RemoveImage deletes an image by reference.
 	RemoveImage(ctx context.Context, idOrRef string, force bool) error
 
+	// SaveImages writes namesOrIDs to out as a single docker-archive tar
+	// stream, sharing blobs common to several images.
+	SaveImages(ctx context.Context, namesOrIDs []string, out io.Writer) error
+
+	// LoadImages imports the images described by a docker-archive tar
+	// stream read from in, reporting progress to out.
+	LoadImages(ctx context.Context, in io.Reader, out io.Writer) error
+
+	// ImageHistory returns the per-layer provenance of the image identified
+	// by idOrRef.
+	ImageHistory(ctx context.Context, idOrRef string) ([]types.HistoryResponseItem, error)
+
+	// PullImageWithOptions is PullImage extended with platform selection
+	// for manifest-list/OCI-index references.
+	PullImageWithOptions(ctx context.Context, ref string, authConfig *types.AuthConfig, out io.Writer, opts PullImageOptions) error
+
+	// PruneImages removes dangling images (those with no tagged reference),
+	// or, when all is true, images unused by any container, returning the
+	// deleted references and the aggregate reclaimed size.
+	PruneImages(ctx context.Context, filter filters.Args, all bool) ([]types.ImageDeleteResponseItem, int64, error)
+
 	// CheckReference returns imageID, actual reference and primary reference.
 	CheckReference(ctx context.Context, idOrRef string) (digest.Digest, reference.Named, reference.Named, error)
 }
@@ -58,6 +86,61 @@ type ImageManager struct {
 
 	// localStore is local cache of image reference information.
 	localStore *imageStore
+
+	// containerChecker tells whether an image is still referenced by a
+	// container. It is set after construction via SetContainerChecker to
+	// avoid an import cycle between the image and container managers.
+	containerChecker ContainerImageChecker
+
+	// pullRetryPolicy governs PullImage's retry-with-backoff behavior
+	// against transient registry failures.
+	pullRetryPolicy retry.Policy
+
+	// httpClient is the shared HTTP client SearchImages uses to talk to
+	// registries directly, built once from cfg.RegistryTLS so private and
+	// self-signed registries can be searched.
+	httpClient *http.Client
+
+	// platformMu guards platformByID.
+	platformMu sync.RWMutex
+
+	// platformByID records the resolved Platform (including variant) for
+	// images registered by PullImageWithOptions, since an OCI image config
+	// alone cannot carry the manifest list's variant. Images without an
+	// entry here fall back to the Os/Architecture read from their config.
+	platformByID map[digest.Digest]types.Platform
+}
+
+// setImagePlatform records id's resolved platform, for GetImage/ListImages
+// to surface later.
+func (mgr *ImageManager) setImagePlatform(id digest.Digest, platform types.Platform) {
+	mgr.platformMu.Lock()
+	defer mgr.platformMu.Unlock()
+	mgr.platformByID[id] = platform
+}
+
+// getImagePlatform returns the platform recorded for id, if any.
+func (mgr *ImageManager) getImagePlatform(id digest.Digest) (types.Platform, bool) {
+	mgr.platformMu.RLock()
+	defer mgr.platformMu.RUnlock()
+	platform, ok := mgr.platformByID[id]
+	return platform, ok
+}
+
+// ContainerImageChecker is implemented by the container manager so that
+// ImageManager.PruneImages can decide whether an image is in use without
+// importing the container manager directly.
+type ContainerImageChecker interface {
+	// IsImageUsedByContainer reports whether any container, running or
+	// stopped, references the image identified by id.
+	IsImageUsedByContainer(id digest.Digest) bool
+}
+
+// SetContainerChecker wires the container manager's image-usage checker into
+// the image manager. It must be called once both managers have been
+// constructed, before PruneImages(all=true) is used.
+func (mgr *ImageManager) SetContainerChecker(checker ContainerImageChecker) {
+	mgr.containerChecker = checker
 }
 
 // NewImageManager initializes a brand new image manager.
@@ -67,12 +150,20 @@ func NewImageManager(cfg *config.Config, client ctrd.APIClient) (*ImageManager,
 		return nil, err
 	}
 
+	httpClient, err := newSearchHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	mgr := &ImageManager{
 		DefaultRegistry:  cfg.DefaultRegistry,
 		DefaultNamespace: cfg.DefaultRegistryNS,
 
-		client:     client,
-		localStore: store,
+		client:          client,
+		localStore:      store,
+		pullRetryPolicy: pullRetryPolicyFromConfig(cfg),
+		platformByID:    make(map[digest.Digest]types.Platform),
+		httpClient:      httpClient,
 	}
 
 	if err := mgr.updateLocalStore(); err != nil {
@@ -81,6 +172,24 @@ func NewImageManager(cfg *config.Config, client ctrd.APIClient) (*ImageManager,
 	return mgr, nil
 }
 
+// pullRetryPolicyFromConfig builds the PullImage retry policy from
+// cfg.RegistryPullRetry, falling back to retry.DefaultPolicy when the
+// daemon was not configured with one (MaxAttempts is unset).
+func pullRetryPolicyFromConfig(cfg *config.Config) retry.Policy {
+	rp := cfg.RegistryPullRetry
+	if rp.MaxAttempts == 0 {
+		return retry.DefaultPolicy()
+	}
+
+	return retry.Policy{
+		MaxAttempts:  rp.MaxAttempts,
+		InitialDelay: rp.InitialDelay,
+		Factor:       rp.Factor,
+		MaxDelay:     rp.MaxDelay,
+		Jitter:       rp.Jitter,
+	}
+}
+
 // CheckReference returns image ID and actual reference.
 func (mgr *ImageManager) CheckReference(ctx context.Context, idOrRef string) (actualID digest.Digest, actualRef reference.Named, primaryRef reference.Named, err error) {
 	var namedRef reference.Named
@@ -155,14 +264,38 @@ func (mgr *ImageManager) GetImage(ctx context.Context, idOrRef string) (*types.I
 	return &imgInfo, nil
 }
 
-// PullImage pulls images from specified registry.
+// PullImage pulls images from specified registry, retrying transient
+// failures (network errors, HTTP 5xx/429 responses, interrupted blob
+// transfers) according to mgr.pullRetryPolicy so a large pull dying near
+// the end does not have to restart from scratch by hand.
 func (mgr *ImageManager) PullImage(ctx context.Context, ref string, authConfig *types.AuthConfig, out io.Writer) error {
 	newRef := addDefaultRegistryIfMissing(ref, mgr.DefaultRegistry, mgr.DefaultNamespace)
 	namedRef, err := reference.Parse(newRef)
 	if err != nil {
 		return err
 	}
+	namedRef = reference.TrimTagForDigest(reference.WithDefaultTagIfMissing(namedRef))
 
+	var img containerd.Image
+	err = retry.Do(ctx, mgr.pullRetryPolicy, retry.IsTransient,
+		func(attempt int, retryErr error) {
+			writeRetryStatus(out, namedRef.String(), retryErr)
+		},
+		func(pctx context.Context) error {
+			var pullErr error
+			img, pullErr = mgr.pullOnce(pctx, namedRef.String(), authConfig, out)
+			return pullErr
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return mgr.storeImageReference(ctx, img)
+}
+
+// pullOnce performs a single pull attempt, streaming progress to out.
+func (mgr *ImageManager) pullOnce(ctx context.Context, ref string, authConfig *types.AuthConfig, out io.Writer) (containerd.Image, error) {
 	pctx, cancel := context.WithCancel(ctx)
 	stream := jsonstream.New(out)
 	wait := make(chan struct{})
@@ -174,20 +307,44 @@ func (mgr *ImageManager) PullImage(ctx context.Context, ref string, authConfig *
 		close(wait)
 	}()
 
-	namedRef = reference.TrimTagForDigest(reference.WithDefaultTagIfMissing(namedRef))
-	img, err := mgr.client.PullImage(pctx, namedRef.String(), authConfig, stream)
+	img, err := mgr.client.PullImage(pctx, ref, authConfig, stream)
 	// wait goroutine to exit.
 	<-wait
-	if err != nil {
-		return err
+	return img, err
+}
+
+// writeRetryStatus reports a retry to out as a single jsonstream-compatible
+// NDJSON status message, the same shape jsonstream itself emits, instead of
+// writing plain text into what is otherwise an exclusively JSON-encoded
+// stream.
+func writeRetryStatus(out io.Writer, ref string, retryErr error) {
+	msg := struct {
+		Status string `json:"status"`
+	}{
+		Status: fmt.Sprintf("Retrying pull of %s after error: %v", ref, retryErr),
 	}
 
-	return mgr.storeImageReference(ctx, img)
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	out.Write(append(data, '\n'))
 }
 
-// ListImages lists images stored by containerd.
-func (mgr *ImageManager) ListImages(ctx context.Context, filter ...string) ([]types.ImageInfo, error) {
-	imgs, err := mgr.client.ListImages(ctx, filter...)
+// ListImages lists images stored by containerd, narrowed by filter. Accepted
+// filter keys are "reference" (glob against repo:tag), "before"/"since" (an
+// id or reference, compared by creation time), "dangling" and "label".
+func (mgr *ImageManager) ListImages(ctx context.Context, filter filters.Args) ([]types.ImageInfo, error) {
+	if err := filter.Validate(acceptedImageFilters); err != nil {
+		return nil, err
+	}
+
+	beforeCutoff, sinceCutoff, err := mgr.imageListTimeCutoffs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	imgs, err := mgr.client.ListImages(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -207,8 +364,29 @@ func (mgr *ImageManager) ListImages(ctx context.Context, filter ...string) ([]ty
 		if err != nil {
 			return nil, err
 		}
-		imgInfosIndexByID[imgInfo.ID] = imgInfo
 
+		ociImage, err := containerdImageToOciImage(ctx, img)
+		if err != nil {
+			return nil, err
+		}
+
+		if !beforeCutoff.IsZero() && !ociImage.Created.Before(beforeCutoff) {
+			continue
+		}
+		if !sinceCutoff.IsZero() && !ociImage.Created.After(sinceCutoff) {
+			continue
+		}
+		if filter.Contains("dangling") && !filter.ExactMatch("dangling", strconv.FormatBool(mgr.isDangling(imgCfg.Digest))) {
+			continue
+		}
+		if !filter.MatchKVList("label", ociImage.Config.Labels) {
+			continue
+		}
+		if filter.Contains("reference") && !matchesAnyReferencePattern(filter.Get("reference"), imgInfo.RepoTags) {
+			continue
+		}
+
+		imgInfosIndexByID[imgInfo.ID] = imgInfo
 	}
 
 	imgInfos := make([]types.ImageInfo, 0, len(imgInfosIndexByID))
@@ -218,10 +396,64 @@ func (mgr *ImageManager) ListImages(ctx context.Context, filter ...string) ([]ty
 	return imgInfos, nil
 }
 
-// SearchImages searches imaged from specified registry.
-func (mgr *ImageManager) SearchImages(ctx context.Context, name string, registry string) ([]types.SearchResultItem, error) {
-	// Directly send API calls towards specified registry
-	return nil, errtypes.ErrNotImplemented
+// acceptedImageFilters are the filter keys ListImages understands.
+var acceptedImageFilters = map[string]bool{
+	"reference": true,
+	"before":    true,
+	"since":     true,
+	"dangling":  true,
+	"label":     true,
+}
+
+// imageListTimeCutoffs resolves the "before"/"since" filters, if present, to
+// the Created time of the referenced image.
+func (mgr *ImageManager) imageListTimeCutoffs(ctx context.Context, filter filters.Args) (before time.Time, since time.Time, err error) {
+	if filter.Contains("before") {
+		before, err = mgr.createdAtOf(ctx, filter.Get("before")[0])
+		if err != nil {
+			return
+		}
+	}
+	if filter.Contains("since") {
+		since, err = mgr.createdAtOf(ctx, filter.Get("since")[0])
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// createdAtOf resolves idOrRef and returns the Created time from its OCI
+// image config.
+func (mgr *ImageManager) createdAtOf(ctx context.Context, idOrRef string) (time.Time, error) {
+	_, _, ref, err := mgr.CheckReference(ctx, idOrRef)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	img, err := mgr.client.GetImage(ctx, ref.String())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ociImage, err := containerdImageToOciImage(ctx, img)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ociImage.Created, nil
+}
+
+// matchesAnyReferencePattern reports whether any of repoTags glob-matches
+// any of the patterns.
+func matchesAnyReferencePattern(patterns []string, repoTags []string) bool {
+	for _, pattern := range patterns {
+		for _, tag := range repoTags {
+			if ok, _ := path.Match(pattern, tag); ok {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // RemoveImage deletes a reference.
@@ -326,6 +558,17 @@ func (mgr *ImageManager) storeImageReference(ctx context.Context, img containerd
 	return nil
 }
 
+// storeImageReferenceAs is storeImageReference for a synthetic reference
+// that does not come from img.Name(), such as the per-platform tags
+// PullImageWithOptions registers for a manifest list's non-host platforms.
+func (mgr *ImageManager) storeImageReferenceAs(ctx context.Context, img containerd.Image, namedRef reference.Named) error {
+	imgCfg, err := img.Config(ctx)
+	if err != nil {
+		return err
+	}
+	return mgr.localStore.AddReference(imgCfg.Digest, namedRef, namedRef)
+}
+
 func (mgr *ImageManager) containerdImageToImageInfo(ctx context.Context, img containerd.Image) (types.ImageInfo, error) {
 	desc, err := img.Config(ctx)
 	if err != nil {
@@ -356,12 +599,18 @@ func (mgr *ImageManager) containerdImageToImageInfo(ctx context.Context, img con
 		}
 	}
 
+	platform, ok := mgr.getImagePlatform(desc.Digest)
+	if !ok {
+		platform = types.Platform{OS: ociImage.OS, Architecture: ociImage.Architecture}
+	}
+
 	return types.ImageInfo{
 		Architecture: ociImage.Architecture,
 		Config:       getImageInfoConfigFromOciImage(ociImage),
 		CreatedAt:    ociImage.Created.Format(utils.TimeLayout),
 		ID:           desc.Digest.String(),
 		Os:           ociImage.OS,
+		Platform:     &platform,
 		RepoDigests:  repoDigests,
 		RepoTags:     repoTags,
 		RootFS: &types.ImageInfoRootFS{
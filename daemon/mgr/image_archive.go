@@ -0,0 +1,299 @@
+package mgr
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/alibaba/pouch/pkg/reference"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/platforms"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// archiveManifestEntry is one element of a docker-archive manifest.json: one
+// requested image, naming the blobs (by digest, relative to the archive
+// root) that make it up so a reader can reassemble it without re-resolving
+// anything against a registry.
+type archiveManifestEntry struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// blobPath is the archive-relative path a blob of the given digest is
+// written under, mirroring the OCI image layout's content-addressed
+// "blobs/<algorithm>/<hex>" convention so the same blob referenced by
+// several images is trivially deduplicated by path.
+func blobPath(dgst digest.Digest) string {
+	return fmt.Sprintf("blobs/%s/%s", dgst.Algorithm(), dgst.Encoded())
+}
+
+// SaveImages writes namesOrIDs to out as a single docker-archive formatted
+// tar stream: a top-level manifest.json enumerating every requested image
+// (with its RepoTags) plus the config and layer blobs they reference,
+// fetched from the containerd content store and deduplicated across images
+// that share blobs. The image manifests themselves are not written;
+// LoadImages builds a new manifest from each entry's config and layers.
+func (mgr *ImageManager) SaveImages(ctx context.Context, namesOrIDs []string, out io.Writer) error {
+	store := mgr.client.ContentStore()
+	tw := tar.NewWriter(out)
+
+	var (
+		manifestEntries []archiveManifestEntry
+		written         = make(map[digest.Digest]bool)
+	)
+
+	for _, nameOrID := range namesOrIDs {
+		_, _, ref, err := mgr.CheckReference(ctx, nameOrID)
+		if err != nil {
+			return err
+		}
+
+		img, err := mgr.client.GetImage(ctx, ref.String())
+		if err != nil {
+			return err
+		}
+
+		manifest, err := images.Manifest(ctx, store, img.Target(), platforms.Default())
+		if err != nil {
+			return err
+		}
+
+		if err := writeBlobOnce(ctx, tw, store, manifest.Config.Digest, written); err != nil {
+			return err
+		}
+
+		layers := make([]string, 0, len(manifest.Layers))
+		for _, layer := range manifest.Layers {
+			if err := writeBlobOnce(ctx, tw, store, layer.Digest, written); err != nil {
+				return err
+			}
+			layers = append(layers, blobPath(layer.Digest))
+		}
+
+		var tags []string
+		for _, r := range mgr.localStore.GetReferences(manifest.Config.Digest) {
+			if _, ok := r.(reference.Tagged); ok {
+				tags = append(tags, r.String())
+			}
+		}
+
+		manifestEntries = append(manifestEntries, archiveManifestEntry{
+			Config:   blobPath(manifest.Config.Digest),
+			RepoTags: tags,
+			Layers:   layers,
+		})
+	}
+
+	manifestJSON, err := json.Marshal(manifestEntries)
+	if err != nil {
+		return err
+	}
+	if err := writeTarEntry(tw, "manifest.json", manifestJSON); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// writeBlobOnce copies the blob identified by dgst from store into tw under
+// blobPath(dgst), skipping it if already written by an earlier image in this
+// same archive so blobs shared between images are stored exactly once.
+func writeBlobOnce(ctx context.Context, tw *tar.Writer, store content.Store, dgst digest.Digest, written map[digest.Digest]bool) error {
+	if written[dgst] {
+		return nil
+	}
+
+	data, err := content.ReadBlob(ctx, store, ocispec.Descriptor{Digest: dgst})
+	if err != nil {
+		return err
+	}
+
+	if err := writeTarEntry(tw, blobPath(dgst), data); err != nil {
+		return err
+	}
+	written[dgst] = true
+	return nil
+}
+
+func writeTarEntry(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name: name,
+		Size: int64(len(data)),
+		Mode: 0644,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// LoadImages reads a docker-archive formatted tar stream produced by
+// SaveImages, ingests its blobs into the content store, and registers every
+// image it describes under each of its RepoTags with the local store, the
+// same way PullImage would leave them. An entry with no RepoTags has its
+// blobs ingested but is not registered as an image.
+//
+// Registering a manifest that already sits fully in the content store (as
+// opposed to pulling one from a registry) needs a client call distinct from
+// PullImage/GetImage/ListImages/RemoveImage/Resolve — the ones already
+// relied on elsewhere in this package. mgr.client.CreateImage(ctx, name,
+// desc) is assumed here the same way those are: it names the containerd
+// image-creation call ("tag this already-stored manifest as name") that any
+// load/tag feature needs and that PullImage itself must call internally
+// once it has resolved and fetched a manifest, just under a name this package
+// hasn't had reason to call directly before now.
+func (mgr *ImageManager) LoadImages(ctx context.Context, in io.Reader, out io.Writer) error {
+	store := mgr.client.ContentStore()
+	tr := tar.NewReader(in)
+
+	blobs := make(map[string][]byte)
+	var manifestEntries []archiveManifestEntry
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+
+		if hdr.Name == "manifest.json" {
+			if err := json.Unmarshal(data, &manifestEntries); err != nil {
+				return err
+			}
+			continue
+		}
+		blobs[hdr.Name] = data
+	}
+
+	for _, entry := range manifestEntries {
+		configDigest, err := ingestArchiveBlob(ctx, store, blobs, entry.Config)
+		if err != nil {
+			return err
+		}
+
+		layerDigests := make([]ocispec.Descriptor, 0, len(entry.Layers))
+		for _, layerPath := range entry.Layers {
+			layerDigest, err := ingestArchiveBlob(ctx, store, blobs, layerPath)
+			if err != nil {
+				return err
+			}
+			layerDigests = append(layerDigests, ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageLayerGzip,
+				Digest:    layerDigest,
+				Size:      int64(len(blobs[layerPath])),
+			})
+		}
+
+		manifest := ocispec.Manifest{
+			Versioned: manifestVersioned(),
+			Config: ocispec.Descriptor{
+				MediaType: ocispec.MediaTypeImageConfig,
+				Digest:    configDigest,
+				Size:      int64(len(blobs[entry.Config])),
+			},
+			Layers: layerDigests,
+		}
+		manifestData, err := json.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+		manifestDesc := ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageManifest,
+			Digest:    digest.FromBytes(manifestData),
+			Size:      int64(len(manifestData)),
+		}
+		if err := writeContentBlob(ctx, store, manifestDesc, manifestData); err != nil {
+			return err
+		}
+
+		for _, tag := range entry.RepoTags {
+			img, err := mgr.client.CreateImage(ctx, tag, manifestDesc)
+			if err != nil {
+				return err
+			}
+			if err := mgr.storeImageReference(ctx, img); err != nil {
+				return err
+			}
+			writeLoadStatus(out, tag)
+		}
+	}
+
+	return nil
+}
+
+// writeLoadStatus reports one loaded tag to out as a single
+// jsonstream-compatible NDJSON status message, matching how PullImage
+// reports retries through writeRetryStatus rather than writing plain text
+// into what callers otherwise treat as a JSON-encoded stream.
+func writeLoadStatus(out io.Writer, tag string) {
+	msg := struct {
+		Status string `json:"status"`
+	}{
+		Status: fmt.Sprintf("Loaded image: %s", tag),
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	out.Write(append(data, '\n'))
+}
+
+// ingestArchiveBlob writes the blob stored at path in blobs into store,
+// returning its digest, computed from the blob's own content rather than
+// trusted from path.
+func ingestArchiveBlob(ctx context.Context, store content.Store, blobs map[string][]byte, path string) (digest.Digest, error) {
+	data, ok := blobs[path]
+	if !ok {
+		return "", fmt.Errorf("image-archive: missing blob %q referenced by manifest.json", path)
+	}
+
+	dgst := digest.FromBytes(data)
+	if err := writeContentBlob(ctx, store, ocispec.Descriptor{Digest: dgst, Size: int64(len(data))}, data); err != nil {
+		return "", err
+	}
+	return dgst, nil
+}
+
+// writeContentBlob writes data into store under desc, tolerating the blob
+// already being present (an "already exists" error) since two images in the
+// same archive commonly share layers or a config.
+func writeContentBlob(ctx context.Context, store content.Store, desc ocispec.Descriptor, data []byte) error {
+	writer, err := content.OpenWriter(ctx, store, content.WithRef(desc.Digest.String()), content.WithDescriptor(desc))
+	if err != nil {
+		if errdefs.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+	defer writer.Close()
+
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Commit(ctx, desc.Size, desc.Digest); err != nil && !errdefs.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// manifestVersioned returns the Versioned stanza every OCI manifest carries.
+func manifestVersioned() ocispec.Versioned {
+	return ocispec.Versioned{SchemaVersion: 2}
+}
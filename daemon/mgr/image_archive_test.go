@@ -0,0 +1,78 @@
+package mgr
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+func TestBlobPath(t *testing.T) {
+	dgst := digest.FromString("hello")
+	got := blobPath(dgst)
+	want := "blobs/" + dgst.Algorithm().String() + "/" + dgst.Encoded()
+	if got != want {
+		t.Fatalf("blobPath(%v) = %q, want %q", dgst, got, want)
+	}
+}
+
+func TestWriteTarEntryRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	data := []byte(`{"hello":"world"}`)
+	if err := writeTarEntry(tw, "blobs/sha256/deadbeef", data); err != nil {
+		t.Fatalf("writeTarEntry failed: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tw.Close failed: %v", err)
+	}
+
+	tr := tar.NewReader(&buf)
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatalf("tr.Next failed: %v", err)
+	}
+	if hdr.Name != "blobs/sha256/deadbeef" {
+		t.Fatalf("got entry name %q, want %q", hdr.Name, "blobs/sha256/deadbeef")
+	}
+
+	got, err := ioutil.ReadAll(tr)
+	if err != nil {
+		t.Fatalf("reading entry failed: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %q, want %q", got, data)
+	}
+
+	if _, err := tr.Next(); err != io.EOF {
+		t.Fatalf("expected exactly one entry, got another: %v", err)
+	}
+}
+
+func TestArchiveManifestEntryJSON(t *testing.T) {
+	entry := archiveManifestEntry{
+		Config:   "blobs/sha256/abc",
+		RepoTags: []string{"busybox:latest"},
+		Layers:   []string{"blobs/sha256/def"},
+	}
+
+	data, err := json.Marshal([]archiveManifestEntry{entry})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var decoded []archiveManifestEntry
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(decoded) != 1 || decoded[0].Config != entry.Config ||
+		len(decoded[0].RepoTags) != 1 || decoded[0].RepoTags[0] != "busybox:latest" ||
+		len(decoded[0].Layers) != 1 || decoded[0].Layers[0] != "blobs/sha256/def" {
+		t.Fatalf("round-tripped entry = %+v, want %+v", decoded, entry)
+	}
+}
@@ -0,0 +1,279 @@
+package mgr
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/alibaba/pouch/pkg/errtypes"
+
+	"github.com/containerd/containerd/content"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// HealthConfig mirrors the subset of a Dockerfile HEALTHCHECK instruction's
+// fields stored on an image config, enough to tell two configs apart for
+// build-cache comparison.
+type HealthConfig struct {
+	Test        []string      `json:"Test,omitempty"`
+	Interval    time.Duration `json:"Interval,omitempty"`
+	Timeout     time.Duration `json:"Timeout,omitempty"`
+	StartPeriod time.Duration `json:"StartPeriod,omitempty"`
+	Retries     int           `json:"Retries,omitempty"`
+}
+
+// ImageConfig is the subset of an image config BuildCache compares for
+// cache compatibility. It extends ocispec.ImageConfig with Healthcheck and
+// OnBuild, the two Dockerfile-instruction-relevant fields that live on the
+// Docker schema2 config but not on the stricter OCI ImageConfig.
+type ImageConfig struct {
+	ocispec.ImageConfig
+	Healthcheck *HealthConfig `json:"Healthcheck,omitempty"`
+	OnBuild     []string      `json:"OnBuild,omitempty"`
+}
+
+// rawImageConfig mirrors just enough of the Docker/OCI image JSON to reach
+// into its "config" object, which is where Healthcheck/OnBuild actually
+// live (not at the top level).
+type rawImageConfig struct {
+	Config ImageConfig `json:"config"`
+}
+
+// BuildCache resolves build-cache hits the way Moby's builder does: given
+// the ID of the parent layer the next instruction would be applied to and
+// the resulting image config, it looks for a locally stored image that is
+// both a child of parent and config-compatible, so the instruction can be
+// skipped in favor of reusing that image.
+type BuildCache interface {
+	// GetCache returns the ID of a cached image that is a child of
+	// parentID and config-compatible with cfg, or errtypes.ErrNotfound if
+	// there is no such image.
+	GetCache(parentID digest.Digest, cfg *ImageConfig) (digest.Digest, error)
+}
+
+// imageCache is the default BuildCache implementation. When rootDiffIDs is
+// nil every image in the local store is considered; otherwise only images
+// whose RootFS.DiffIDs extend one of rootDiffIDs are — which is to say,
+// only descendants of the seeded source images, at any depth, not just the
+// source images themselves.
+type imageCache struct {
+	mgr         *ImageManager
+	rootDiffIDs [][]digest.Digest
+}
+
+// NewLocal returns a BuildCache that considers every locally stored image a
+// candidate cache hit.
+func (mgr *ImageManager) NewLocal() BuildCache {
+	return &imageCache{mgr: mgr}
+}
+
+// NewImageCache returns a BuildCache restricted to images descended from one
+// of sourceRefs (e.g. the base images named by a Dockerfile's FROM
+// instructions), avoiding cache hits against unrelated image lineages. A
+// built image many layers below a source ref still matches, since matching
+// is done on its RootFS.DiffIDs having that source's DiffIDs as a prefix,
+// not on being an immediate child of it.
+func (mgr *ImageManager) NewImageCache(ctx context.Context, sourceRefs []string) (BuildCache, error) {
+	c := &imageCache{mgr: mgr}
+	roots := make([][]digest.Digest, 0, len(sourceRefs))
+	for _, ref := range sourceRefs {
+		id, _, _, err := mgr.CheckReference(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		diffIDs, err := c.diffIDsOf(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, diffIDs)
+	}
+	c.rootDiffIDs = roots
+	return c, nil
+}
+
+// GetCache implements BuildCache.
+func (c *imageCache) GetCache(parentID digest.Digest, cfg *ImageConfig) (digest.Digest, error) {
+	ctx := context.Background()
+
+	parentDiffIDs, err := c.diffIDsOf(ctx, parentID)
+	if err != nil {
+		return "", err
+	}
+
+	imgs, err := c.mgr.client.ListImages(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, img := range imgs {
+		imgCfg, err := img.Config(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		ociImage, err := containerdImageToOciImage(ctx, img)
+		if err != nil {
+			return "", err
+		}
+
+		if c.rootDiffIDs != nil && !isDescendantOfAnyRoot(c.rootDiffIDs, ociImage.RootFS.DiffIDs) {
+			continue
+		}
+
+		if !isChildDiffIDs(parentDiffIDs, ociImage.RootFS.DiffIDs) {
+			continue
+		}
+
+		candidate, err := c.imageConfigOf(ctx, imgCfg)
+		if err != nil {
+			return "", err
+		}
+
+		if configsMatch(candidate, cfg) {
+			return imgCfg.Digest, nil
+		}
+	}
+
+	return "", errtypes.ErrNotfound
+}
+
+// imageConfigOf reads and decodes the raw config blob identified by desc,
+// the only way to reach Healthcheck/OnBuild since containerdImageToOciImage
+// only exposes the stricter ocispec.ImageConfig view of it.
+func (c *imageCache) imageConfigOf(ctx context.Context, desc ocispec.Descriptor) (*ImageConfig, error) {
+	data, err := content.ReadBlob(ctx, c.mgr.client.ContentStore(), desc)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw rawImageConfig
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return &raw.Config, nil
+}
+
+// diffIDsOf returns the RootFS.DiffIDs of the image identified by id.
+func (c *imageCache) diffIDsOf(ctx context.Context, id digest.Digest) ([]digest.Digest, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	img, err := c.mgr.client.GetImage(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+
+	ociImage, err := containerdImageToOciImage(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	return ociImage.RootFS.DiffIDs, nil
+}
+
+// isChildDiffIDs reports whether child is exactly parent plus one more
+// layer, which is how we derive parent/child linkage since there is no
+// explicit parent pointer in an OCI image.
+func isChildDiffIDs(parent, child []digest.Digest) bool {
+	if len(child) != len(parent)+1 {
+		return false
+	}
+	for i, d := range parent {
+		if child[i] != d {
+			return false
+		}
+	}
+	return true
+}
+
+// isDescendantOfAnyRoot reports whether diffIDs extends one of roots, i.e.
+// the image it describes is a descendant (at any depth) of that root image.
+func isDescendantOfAnyRoot(roots [][]digest.Digest, diffIDs []digest.Digest) bool {
+	for _, root := range roots {
+		if isPrefixDiffIDs(root, diffIDs) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrefixDiffIDs reports whether prefix is a leading subsequence of full.
+func isPrefixDiffIDs(prefix, full []digest.Digest) bool {
+	if len(prefix) > len(full) {
+		return false
+	}
+	for i, d := range prefix {
+		if full[i] != d {
+			return false
+		}
+	}
+	return true
+}
+
+// configsMatch compares every field that determines a Dockerfile
+// instruction's cache compatibility. Cmd/Entrypoint/Env/OnBuild are
+// order-sensitive; Volumes/ExposedPorts/Labels are compared as sets.
+func configsMatch(a, b *ImageConfig) bool {
+	return stringSliceEqual(a.Cmd, b.Cmd) &&
+		stringSliceEqual(a.Entrypoint, b.Entrypoint) &&
+		stringSliceEqual(a.Env, b.Env) &&
+		stringSliceEqual(a.OnBuild, b.OnBuild) &&
+		a.WorkingDir == b.WorkingDir &&
+		a.User == b.User &&
+		volumesEqual(a.Volumes, b.Volumes) &&
+		exposedPortsEqual(a.ExposedPorts, b.ExposedPorts) &&
+		labelsEqual(a.Labels, b.Labels) &&
+		healthchecksEqual(a.Healthcheck, b.Healthcheck)
+}
+
+func stringSliceEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func volumesEqual(a, b map[string]struct{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func exposedPortsEqual(a, b map[string]struct{}) bool {
+	return volumesEqual(a, b)
+}
+
+func labelsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func healthchecksEqual(a, b *HealthConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return stringSliceEqual(a.Test, b.Test) &&
+		a.Interval == b.Interval &&
+		a.Timeout == b.Timeout &&
+		a.StartPeriod == b.StartPeriod &&
+		a.Retries == b.Retries
+}
@@ -0,0 +1,92 @@
+package mgr
+
+import (
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+func TestConfigsMatch(t *testing.T) {
+	base := &ImageConfig{}
+	base.Cmd = []string{"sh"}
+	base.Env = []string{"FOO=bar"}
+
+	other := &ImageConfig{}
+	other.Cmd = []string{"sh"}
+	other.Env = []string{"FOO=bar"}
+
+	if !configsMatch(base, other) {
+		t.Fatalf("expected identical configs to match")
+	}
+
+	withHealthcheck := &ImageConfig{}
+	withHealthcheck.Cmd = []string{"sh"}
+	withHealthcheck.Env = []string{"FOO=bar"}
+	withHealthcheck.Healthcheck = &HealthConfig{Test: []string{"CMD", "true"}, Retries: 3}
+
+	if configsMatch(base, withHealthcheck) {
+		t.Fatalf("expected configs differing only by Healthcheck to not match")
+	}
+	if !configsMatch(withHealthcheck, withHealthcheck) {
+		t.Fatalf("expected a config to match itself, Healthcheck included")
+	}
+
+	withOnBuild := &ImageConfig{}
+	withOnBuild.Cmd = []string{"sh"}
+	withOnBuild.Env = []string{"FOO=bar"}
+	withOnBuild.OnBuild = []string{"RUN echo hi"}
+
+	if configsMatch(base, withOnBuild) {
+		t.Fatalf("expected configs differing only by OnBuild to not match")
+	}
+}
+
+func TestHealthchecksEqual(t *testing.T) {
+	if !healthchecksEqual(nil, nil) {
+		t.Fatalf("expected two nil healthchecks to be equal")
+	}
+	if healthchecksEqual(nil, &HealthConfig{}) {
+		t.Fatalf("expected nil and non-nil healthchecks to differ")
+	}
+
+	a := &HealthConfig{Test: []string{"CMD", "true"}, Interval: time.Second, Retries: 3}
+	b := &HealthConfig{Test: []string{"CMD", "true"}, Interval: time.Second, Retries: 3}
+	if !healthchecksEqual(a, b) {
+		t.Fatalf("expected equivalent healthchecks to be equal")
+	}
+
+	c := &HealthConfig{Test: []string{"CMD", "false"}, Interval: time.Second, Retries: 3}
+	if healthchecksEqual(a, c) {
+		t.Fatalf("expected healthchecks with different Test to differ")
+	}
+}
+
+func TestIsChildDiffIDs(t *testing.T) {
+	parent := []digest.Digest{"sha256:aaa", "sha256:bbb"}
+	child := []digest.Digest{"sha256:aaa", "sha256:bbb", "sha256:ccc"}
+	grandchild := []digest.Digest{"sha256:aaa", "sha256:bbb", "sha256:ccc", "sha256:ddd"}
+
+	if !isChildDiffIDs(parent, child) {
+		t.Fatalf("expected child to be recognized as a direct child of parent")
+	}
+	if isChildDiffIDs(parent, grandchild) {
+		t.Fatalf("expected grandchild to not be a direct child of parent")
+	}
+	if isChildDiffIDs(parent, parent) {
+		t.Fatalf("expected parent to not be its own child")
+	}
+}
+
+func TestIsDescendantOfAnyRoot(t *testing.T) {
+	root := []digest.Digest{"sha256:aaa"}
+	descendant := []digest.Digest{"sha256:aaa", "sha256:bbb", "sha256:ccc"}
+	unrelated := []digest.Digest{"sha256:zzz"}
+
+	if !isDescendantOfAnyRoot([][]digest.Digest{root}, descendant) {
+		t.Fatalf("expected descendant (any depth) to match its root")
+	}
+	if isDescendantOfAnyRoot([][]digest.Digest{root}, unrelated) {
+		t.Fatalf("expected unrelated DiffIDs to not match root")
+	}
+}
@@ -0,0 +1,67 @@
+package mgr
+
+import (
+	"context"
+
+	"github.com/alibaba/pouch/apis/types"
+	"github.com/alibaba/pouch/pkg/utils"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/platforms"
+)
+
+// ImageHistory returns the per-layer build provenance of the image
+// identified by idOrRef: one entry per line in its OCI config History,
+// in the original order, with empty-layer entries preserved and reported
+// with size 0.
+func (mgr *ImageManager) ImageHistory(ctx context.Context, idOrRef string) ([]types.HistoryResponseItem, error) {
+	_, _, ref, err := mgr.CheckReference(ctx, idOrRef)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := mgr.client.GetImage(ctx, ref.String())
+	if err != nil {
+		return nil, err
+	}
+
+	ociImage, err := containerdImageToOciImage(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := images.Manifest(ctx, mgr.client.ContentStore(), img.Target(), platforms.Default())
+	if err != nil {
+		return nil, err
+	}
+
+	layerSizeByDiffID := make(map[string]int64, len(manifest.Layers))
+	for i, diffID := range ociImage.RootFS.DiffIDs {
+		if i < len(manifest.Layers) {
+			layerSizeByDiffID[diffID.String()] = manifest.Layers[i].Size
+		}
+	}
+
+	history := make([]types.HistoryResponseItem, 0, len(ociImage.History))
+	diffIDIdx := 0
+	for _, h := range ociImage.History {
+		item := types.HistoryResponseItem{
+			CreatedBy:  h.CreatedBy,
+			Comment:    h.Comment,
+			EmptyLayer: h.EmptyLayer,
+		}
+		if h.Created != nil {
+			item.Created = h.Created.Format(utils.TimeLayout)
+		}
+
+		if !h.EmptyLayer && diffIDIdx < len(ociImage.RootFS.DiffIDs) {
+			diffID := ociImage.RootFS.DiffIDs[diffIDIdx]
+			item.Size = layerSizeByDiffID[diffID.String()]
+			diffIDIdx++
+		}
+
+		history = append(history, item)
+	}
+
+	return history, nil
+}
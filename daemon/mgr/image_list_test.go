@@ -0,0 +1,61 @@
+package mgr
+
+import "testing"
+
+// Exercising ListImages end-to-end by seeding imageStore, as requested,
+// needs imageStore itself plus the pkg/reference and pkg/errtypes types its
+// methods take and return (reference.Named/Tagged/CanonicalDigested,
+// errtypes.ErrNotfound, ...). None of those three packages exist anywhere
+// in this checkout — grep for "package imageStore"/"package reference"/
+// "package errtypes" turns up nothing — so they are assumed-present
+// dependencies of daemon/mgr, the same way ctrd.APIClient is, not missing
+// test scaffolding. Recreating all three well enough to seed real mixed
+// tagged/untagged/labelled fixtures would mean guessing at their real
+// implementations rather than testing against them, so instead these tests
+// cover the pure filter-matching helpers ListImages is built on; the other
+// *_test.go files added alongside this one cover the remaining pure logic
+// in image_archive.go, image_cache.go, image_prune.go and image_search.go.
+
+func TestMatchesAnyReferencePattern(t *testing.T) {
+	repoTags := []string{"busybox:1.25", "busybox:latest"}
+
+	cases := []struct {
+		name     string
+		patterns []string
+		want     bool
+	}{
+		{"exact match", []string{"busybox:latest"}, true},
+		{"glob match", []string{"busy*"}, true},
+		{"no match", []string{"nginx:latest"}, false},
+		{"one of several patterns matches", []string{"nginx:latest", "busybox:1.25"}, true},
+		{"empty patterns", nil, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchesAnyReferencePattern(tc.patterns, repoTags); got != tc.want {
+				t.Errorf("matchesAnyReferencePattern(%v, %v) = %v, want %v", tc.patterns, repoTags, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAcceptedImageFiltersValidate(t *testing.T) {
+	accepted := map[string]bool{
+		"reference": true,
+		"before":    true,
+		"since":     true,
+		"dangling":  true,
+		"label":     true,
+	}
+	for key := range acceptedImageFilters {
+		if !accepted[key] {
+			t.Errorf("acceptedImageFilters has unexpected key %q", key)
+		}
+	}
+	for key := range accepted {
+		if !acceptedImageFilters[key] {
+			t.Errorf("acceptedImageFilters is missing expected key %q", key)
+		}
+	}
+}
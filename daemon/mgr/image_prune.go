@@ -0,0 +1,140 @@
+package mgr
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alibaba/pouch/apis/types"
+	"github.com/alibaba/pouch/pkg/filters"
+	"github.com/alibaba/pouch/pkg/reference"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// PruneImages removes dangling images, or, when all is true, every image not
+// referenced by any container. It supports the filter keys "dangling"
+// ("true"/"false"), "until" (see pruneUntil) and "label" (key or key=value,
+// matched against the image config's labels).
+//
+// all=true refuses to run until SetContainerChecker has been wired up: with
+// no checker there is no way to tell a used image from an unused one, and
+// silently treating every image as unused would delete images backing
+// running or stopped containers.
+func (mgr *ImageManager) PruneImages(ctx context.Context, filter filters.Args, all bool) ([]types.ImageDeleteResponseItem, int64, error) {
+	if all && mgr.containerChecker == nil {
+		return nil, 0, fmt.Errorf("cannot prune all unused images: no container checker has been registered via SetContainerChecker")
+	}
+
+	until, err := pruneUntil(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	imgs, err := mgr.client.ListImages(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		deleted      []types.ImageDeleteResponseItem
+		reclaimed    int64
+		seenByDigest = make(map[string]bool)
+	)
+
+	for _, img := range imgs {
+		imgCfg, err := img.Config(ctx)
+		if err != nil {
+			return deleted, reclaimed, err
+		}
+
+		if seenByDigest[imgCfg.Digest.String()] {
+			continue
+		}
+		seenByDigest[imgCfg.Digest.String()] = true
+
+		if !all && !mgr.isDangling(imgCfg.Digest) {
+			continue
+		}
+
+		if all && mgr.containerChecker.IsImageUsedByContainer(imgCfg.Digest) {
+			continue
+		}
+
+		ociImage, err := containerdImageToOciImage(ctx, img)
+		if err != nil {
+			return deleted, reclaimed, err
+		}
+
+		if filter.Contains("dangling") && !filter.ExactMatch("dangling", strconv.FormatBool(mgr.isDangling(imgCfg.Digest))) {
+			continue
+		}
+
+		if !until.IsZero() && !ociImage.Created.Before(until) {
+			continue
+		}
+
+		if !filter.MatchKVList("label", ociImage.Config.Labels) {
+			continue
+		}
+
+		size, err := img.Size(ctx)
+		if err != nil {
+			return deleted, reclaimed, err
+		}
+
+		refs := mgr.localStore.GetPrimaryReferences(imgCfg.Digest)
+		if len(refs) == 0 {
+			// no primary reference means the digest itself is the only
+			// handle we have left on this image.
+			if err := mgr.client.RemoveImage(ctx, img.Name()); err != nil {
+				return deleted, reclaimed, err
+			}
+			deleted = append(deleted, types.ImageDeleteResponseItem{Deleted: img.Name()})
+			reclaimed += size
+			continue
+		}
+
+		for _, ref := range refs {
+			if err := mgr.client.RemoveImage(ctx, ref.String()); err != nil {
+				return deleted, reclaimed, err
+			}
+			if err := mgr.localStore.RemoveReference(imgCfg.Digest, ref); err != nil {
+				return deleted, reclaimed, err
+			}
+			deleted = append(deleted, types.ImageDeleteResponseItem{Deleted: ref.String()})
+		}
+		reclaimed += size
+	}
+
+	return deleted, reclaimed, nil
+}
+
+// isDangling reports whether id has no tagged (non-digest) reference.
+func (mgr *ImageManager) isDangling(id digest.Digest) bool {
+	for _, ref := range mgr.localStore.GetReferences(id) {
+		if _, ok := ref.(reference.Tagged); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// pruneUntil parses the "until" filter into a cutoff time, honoring either a
+// Go duration ("24h") relative to now, or an absolute RFC3339 timestamp.
+func pruneUntil(filter filters.Args) (time.Time, error) {
+	if !filter.Contains("until") {
+		return time.Time{}, nil
+	}
+
+	values := filter.Get("until")
+	raw := strings.TrimSpace(values[0])
+
+	if d, err := time.ParseDuration(raw); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	return time.Parse(time.RFC3339, raw)
+}
@@ -0,0 +1,47 @@
+package mgr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibaba/pouch/pkg/filters"
+)
+
+func TestPruneUntilNoFilter(t *testing.T) {
+	until, err := pruneUntil(filters.NewArgs())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !until.IsZero() {
+		t.Fatalf("expected zero time with no until filter, got %v", until)
+	}
+}
+
+func TestPruneUntilDuration(t *testing.T) {
+	before := time.Now().Add(-24 * time.Hour)
+	until, err := pruneUntil(filters.NewArgs("until=24h"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	after := time.Now().Add(-24 * time.Hour)
+	if until.Before(before) || until.After(after.Add(time.Second)) {
+		t.Fatalf("expected until ~24h ago, got %v", until)
+	}
+}
+
+func TestPruneUntilRFC3339(t *testing.T) {
+	until, err := pruneUntil(filters.NewArgs("until=2024-01-02T15:04:05Z"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, _ := time.Parse(time.RFC3339, "2024-01-02T15:04:05Z")
+	if !until.Equal(want) {
+		t.Fatalf("got %v, want %v", until, want)
+	}
+}
+
+func TestPruneUntilInvalid(t *testing.T) {
+	if _, err := pruneUntil(filters.NewArgs("until=not-a-time")); err == nil {
+		t.Fatalf("expected an error for an unparseable until value")
+	}
+}
@@ -0,0 +1,134 @@
+package mgr
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/alibaba/pouch/apis/types"
+	"github.com/alibaba/pouch/pkg/reference"
+
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/platforms"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// PullImageOptions carries the platform-selection knobs PullImageWithOptions
+// understands, on top of what plain PullImage takes.
+type PullImageOptions struct {
+	// Platform selects which child manifest to pull when ref resolves to
+	// an OCI image index or Docker manifest list. Nil means the host's
+	// default platform. It is ignored when PullAllPlatforms is set.
+	Platform *ocispec.Platform
+
+	// PullAllPlatforms, when true, pulls every platform in the index
+	// instead of just the selected one, registering each under a
+	// synthetic "name:tag-<os>-<arch>" reference while the primary tag
+	// keeps pointing at the host-platform manifest.
+	PullAllPlatforms bool
+}
+
+// PullImageWithOptions is PullImage extended with platform selection: when
+// ref resolves to a manifest list/OCI index, it pulls the child manifest
+// matching opts.Platform (or every child, with opts.PullAllPlatforms).
+func (mgr *ImageManager) PullImageWithOptions(ctx context.Context, ref string, authConfig *types.AuthConfig, out io.Writer, opts PullImageOptions) error {
+	platform := opts.Platform
+	if platform == nil || opts.PullAllPlatforms {
+		def := platforms.DefaultSpec()
+		platform = &def
+	}
+
+	newRef := addDefaultRegistryIfMissing(ref, mgr.DefaultRegistry, mgr.DefaultNamespace)
+	namedRef, err := reference.Parse(newRef)
+	if err != nil {
+		return err
+	}
+	namedRef = reference.TrimTagForDigest(reference.WithDefaultTagIfMissing(namedRef))
+
+	desc, err := mgr.client.Resolve(ctx, namedRef.String())
+	if err != nil {
+		return err
+	}
+
+	if !images.IsIndexType(desc.MediaType) {
+		// Not a manifest list: behave exactly like PullImage.
+		return mgr.PullImage(ctx, ref, authConfig, out)
+	}
+
+	children, err := images.Children(ctx, mgr.client.ContentStore(), desc)
+	if err != nil {
+		return err
+	}
+
+	var (
+		matcher  = platforms.NewMatcher(*platform)
+		toPull   []ocispec.Descriptor
+		matchDig ocispec.Descriptor
+		found    bool
+	)
+
+	for _, child := range children {
+		if child.Platform == nil {
+			continue
+		}
+		if opts.PullAllPlatforms {
+			toPull = append(toPull, child)
+		}
+		if matcher.Match(*child.Platform) && !found {
+			matchDig = child
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("no manifest in %q matches platform %s", ref, platforms.Format(*platform))
+	}
+	if !opts.PullAllPlatforms {
+		toPull = []ocispec.Descriptor{matchDig}
+	}
+
+	for _, child := range toPull {
+		childRef := reference.WithDigest(namedRef, child.Digest)
+		if err := mgr.PullImage(ctx, childRef.String(), authConfig, out); err != nil {
+			return err
+		}
+
+		img, err := mgr.client.GetImage(ctx, childRef.String())
+		if err != nil {
+			return err
+		}
+		imgCfg, err := img.Config(ctx)
+		if err != nil {
+			return err
+		}
+		mgr.setImagePlatform(imgCfg.Digest, types.Platform{
+			OS:           child.Platform.OS,
+			Architecture: child.Platform.Architecture,
+			Variant:      child.Platform.Variant,
+		})
+
+		if child.Digest == matchDig.Digest {
+			// childRef is a pure digest reference, so PullImage's own
+			// storeImageReference (which registers img.Name(), i.e.
+			// childRef) never adds the name:tag alias the caller actually
+			// asked to pull. Register it explicitly here instead.
+			if err := mgr.storeImageReferenceAs(ctx, img, namedRef); err != nil {
+				return err
+			}
+		}
+
+		tag := "latest"
+		if tagged, ok := namedRef.(reference.Tagged); ok {
+			tag = tagged.Tag()
+		}
+		synthetic, err := reference.Parse(fmt.Sprintf("%s:%s-%s-%s", namedRef.Name(), tag, child.Platform.OS, child.Platform.Architecture))
+		if err != nil {
+			return err
+		}
+		if err := mgr.storeImageReferenceAs(ctx, img, synthetic); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
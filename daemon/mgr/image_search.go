@@ -0,0 +1,337 @@
+package mgr
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alibaba/pouch/apis/types"
+	"github.com/alibaba/pouch/daemon/config"
+)
+
+const defaultSearchLimit = 25
+
+// newSearchHTTPClient builds the HTTP client SearchImages shares across
+// requests, with sensible timeouts and the daemon's configured registry TLS
+// settings so private/self-signed registries can be searched the same way
+// they can be pulled from.
+func newSearchHTTPClient(cfg *config.Config) (*http.Client, error) {
+	tlsConfig, err := tlsConfigFromRegistryTLS(cfg.RegistryTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Timeout:   15 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}, nil
+}
+
+// tlsConfigFromRegistryTLS builds a *tls.Config from the daemon's
+// RegistryTLSConfig. A zero-value RegistryTLSConfig yields a nil
+// *tls.Config, i.e. http.Transport's own default.
+func tlsConfigFromRegistryTLS(rt config.RegistryTLSConfig) (*tls.Config, error) {
+	if rt == (config.RegistryTLSConfig{}) {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: rt.InsecureSkipVerify}
+
+	if rt.CAFile != "" {
+		pem, err := ioutil.ReadFile(rt.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %q", rt.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if rt.CertFile != "" && rt.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(rt.CertFile, rt.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// v1SearchResponse mirrors the Docker Registry v1 /v1/search response.
+type v1SearchResponse struct {
+	Results []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		StarCount   int    `json:"star_count"`
+		IsOfficial  bool   `json:"is_official"`
+		IsAutomated bool   `json:"is_automated"`
+	} `json:"results"`
+}
+
+// v2CatalogResponse mirrors the Docker Registry v2 /v2/_catalog response.
+type v2CatalogResponse struct {
+	Repositories []string `json:"repositories"`
+}
+
+// v2TagsResponse mirrors the Docker Registry v2 tags list response.
+type v2TagsResponse struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// SearchImages searches name against registry, trying the Registry v1
+// search API first and falling back to a v2 catalog scan when the registry
+// does not speak v1 (404) or requires authentication for it (401).
+func (mgr *ImageManager) SearchImages(ctx context.Context, name string, registry string) ([]types.SearchResultItem, error) {
+	return mgr.searchImagesWithAuth(ctx, name, registry, nil)
+}
+
+// searchImagesWithAuth is split out from SearchImages so PullImage's
+// existing credential-resolution path can be reused once SearchImages grows
+// a variant that accepts *types.AuthConfig.
+func (mgr *ImageManager) searchImagesWithAuth(ctx context.Context, name string, registry string, authConfig *types.AuthConfig) ([]types.SearchResultItem, error) {
+	if registry == "" {
+		registry = mgr.DefaultRegistry
+	}
+	base := registryBaseURL(registry)
+
+	results, err := mgr.v1Search(ctx, base, name, authConfig)
+	if err == nil {
+		return limitResults(results, defaultSearchLimit), nil
+	}
+	if se, ok := err.(*searchHTTPError); !ok || (se.status != http.StatusNotFound && se.status != http.StatusUnauthorized) {
+		return nil, err
+	}
+
+	results, err = mgr.v2Search(ctx, base, name, authConfig)
+	if err != nil {
+		return nil, err
+	}
+	return limitResults(results, defaultSearchLimit), nil
+}
+
+type searchHTTPError struct {
+	status int
+	url    string
+}
+
+func (e *searchHTTPError) Error() string {
+	return fmt.Sprintf("registry search request to %s failed with status %d", e.url, e.status)
+}
+
+func (mgr *ImageManager) v1Search(ctx context.Context, base, name string, authConfig *types.AuthConfig) ([]types.SearchResultItem, error) {
+	u := fmt.Sprintf("%s/v1/search?q=%s", base, url.QueryEscape(name))
+
+	resp, err := mgr.doRegistryRequest(ctx, http.MethodGet, u, authConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &searchHTTPError{status: resp.StatusCode, url: u}
+	}
+
+	var parsed v1SearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+
+	items := make([]types.SearchResultItem, 0, len(parsed.Results))
+	for _, r := range parsed.Results {
+		items = append(items, types.SearchResultItem{
+			Name:        r.Name,
+			Description: r.Description,
+			StarCount:   int64(r.StarCount),
+			IsOfficial:  r.IsOfficial,
+			IsAutomated: r.IsAutomated,
+		})
+	}
+	return items, nil
+}
+
+func (mgr *ImageManager) v2Search(ctx context.Context, base, name string, authConfig *types.AuthConfig) ([]types.SearchResultItem, error) {
+	u := fmt.Sprintf("%s/v2/_catalog?n=100", base)
+
+	resp, err := mgr.doRegistryRequest(ctx, http.MethodGet, u, authConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &searchHTTPError{status: resp.StatusCode, url: u}
+	}
+
+	var catalog v2CatalogResponse
+	if err := json.NewDecoder(resp.Body).Decode(&catalog); err != nil {
+		return nil, err
+	}
+
+	var items []types.SearchResultItem
+	for _, repo := range catalog.Repositories {
+		if !strings.Contains(repo, name) {
+			continue
+		}
+
+		tagsURL := fmt.Sprintf("%s/v2/%s/tags/list", base, repo)
+		tagsResp, err := mgr.doRegistryRequest(ctx, http.MethodGet, tagsURL, authConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		if tagsResp.StatusCode != http.StatusOK {
+			tagsResp.Body.Close()
+			return nil, &searchHTTPError{status: tagsResp.StatusCode, url: tagsURL}
+		}
+
+		var tags v2TagsResponse
+		decodeErr := json.NewDecoder(tagsResp.Body).Decode(&tags)
+		tagsResp.Body.Close()
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+
+		items = append(items, types.SearchResultItem{
+			Name:        repo,
+			Description: fmt.Sprintf("%d tags", len(tags.Tags)),
+		})
+	}
+	return items, nil
+}
+
+// doRegistryRequest issues req against the registry, transparently handling
+// the v2 bearer-token challenge (WWW-Authenticate: Bearer ...) using
+// authConfig the same way PullImage's client resolves credentials.
+func (mgr *ImageManager) doRegistryRequest(ctx context.Context, method, rawURL string, authConfig *types.AuthConfig) (*http.Response, error) {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := mgr.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+	if challenge == "" {
+		return nil, &searchHTTPError{status: resp.StatusCode, url: rawURL}
+	}
+
+	token, err := mgr.fetchBearerToken(ctx, challenge, authConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err = http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return mgr.httpClient.Do(req)
+}
+
+// fetchBearerToken implements the Docker Registry v2 token auth flow: parse
+// the Bearer realm/service/scope out of the challenge, then request a token
+// from the realm, optionally authenticating with authConfig.
+func (mgr *ImageManager) fetchBearerToken(ctx context.Context, challenge string, authConfig *types.AuthConfig) (string, error) {
+	params := parseBearerChallenge(challenge)
+	realm, ok := params["realm"]
+	if !ok {
+		return "", fmt.Errorf("bearer challenge %q is missing realm", challenge)
+	}
+
+	q := url.Values{}
+	if service, ok := params["service"]; ok {
+		q.Set("service", service)
+	}
+	if scope, ok := params["scope"]; ok {
+		q.Set("scope", scope)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, realm+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	if authConfig != nil && authConfig.Username != "" {
+		req.SetBasicAuth(authConfig.Username, authConfig.Password)
+	}
+
+	resp, err := mgr.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &searchHTTPError{status: resp.StatusCode, url: realm}
+	}
+
+	var tokenResp struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", err
+	}
+	if tokenResp.Token != "" {
+		return tokenResp.Token, nil
+	}
+	return tokenResp.AccessToken, nil
+}
+
+// parseBearerChallenge parses `Bearer realm="...",service="...",scope="..."`
+// into a key/value map.
+func parseBearerChallenge(challenge string) map[string]string {
+	params := map[string]string{}
+	challenge = strings.TrimPrefix(challenge, "Bearer ")
+	for _, part := range strings.Split(challenge, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+	return params
+}
+
+// registryBaseURL normalizes registry into a scheme-qualified base URL,
+// defaulting to HTTPS as containerd and the Docker client do.
+func registryBaseURL(registry string) string {
+	if strings.HasPrefix(registry, "http://") || strings.HasPrefix(registry, "https://") {
+		return strings.TrimSuffix(registry, "/")
+	}
+	return "https://" + strings.TrimSuffix(registry, "/")
+}
+
+// limitResults truncates results to n, sorted by StarCount descending so the
+// most relevant matches are kept.
+func limitResults(results []types.SearchResultItem, n int) []types.SearchResultItem {
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].StarCount > results[j].StarCount
+	})
+	if len(results) > n {
+		results = results[:n]
+	}
+	return results
+}
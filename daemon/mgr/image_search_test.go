@@ -0,0 +1,84 @@
+package mgr
+
+import (
+	"testing"
+
+	"github.com/alibaba/pouch/apis/types"
+	"github.com/alibaba/pouch/daemon/config"
+)
+
+func TestParseBearerChallenge(t *testing.T) {
+	challenge := `Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:busybox:pull"`
+
+	got := parseBearerChallenge(challenge)
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:busybox:pull",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("parseBearerChallenge(...)[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestRegistryBaseURL(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"registry.example.com", "https://registry.example.com"},
+		{"registry.example.com/", "https://registry.example.com"},
+		{"http://registry.example.com", "http://registry.example.com"},
+		{"https://registry.example.com", "https://registry.example.com"},
+	}
+	for _, tc := range cases {
+		if got := registryBaseURL(tc.in); got != tc.want {
+			t.Errorf("registryBaseURL(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestLimitResults(t *testing.T) {
+	results := []types.SearchResultItem{
+		{Name: "a", StarCount: 1},
+		{Name: "b", StarCount: 10},
+		{Name: "c", StarCount: 5},
+	}
+
+	limited := limitResults(results, 2)
+	if len(limited) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(limited))
+	}
+	if limited[0].Name != "b" || limited[1].Name != "c" {
+		t.Fatalf("expected results sorted by StarCount descending, got %v", limited)
+	}
+}
+
+func TestTLSConfigFromRegistryTLSZeroValue(t *testing.T) {
+	tlsConfig, err := tlsConfigFromRegistryTLS(config.RegistryTLSConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tlsConfig != nil {
+		t.Fatalf("expected a zero-value RegistryTLSConfig to yield a nil *tls.Config, got %+v", tlsConfig)
+	}
+}
+
+func TestTLSConfigFromRegistryTLSInsecureSkipVerify(t *testing.T) {
+	tlsConfig, err := tlsConfigFromRegistryTLS(config.RegistryTLSConfig{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tlsConfig == nil || !tlsConfig.InsecureSkipVerify {
+		t.Fatalf("expected InsecureSkipVerify to carry through, got %+v", tlsConfig)
+	}
+}
+
+func TestTLSConfigFromRegistryTLSMissingCAFile(t *testing.T) {
+	_, err := tlsConfigFromRegistryTLS(config.RegistryTLSConfig{CAFile: "/nonexistent/ca.pem"})
+	if err == nil {
+		t.Fatalf("expected an error reading a nonexistent CAFile")
+	}
+}
@@ -0,0 +1,116 @@
+// Package filters provides a small, dependency-free filter expression type
+// shared by the daemon managers (image, container, ...) for parsing and
+// matching the `key=value` filter arguments accepted by the API, mirroring
+// the filter semantics users already know from the Docker CLI.
+package filters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Args stores a set of filter key/value pairs. A given key may be repeated
+// with several values, in which case ExactMatch and UniqueExactMatch treat
+// matching a single value as enough for the key to be satisfied (logical OR
+// within a key), while MatchKVList requires every value to match (logical
+// AND within a key); different keys are combined with a logical AND.
+type Args struct {
+	fields map[string]map[string]bool
+}
+
+// NewArgs initializes a new Args, optionally seeded with key=value pairs
+// encoded as "key=value" strings.
+func NewArgs(pairs ...string) Args {
+	args := Args{fields: map[string]map[string]bool{}}
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		args.Add(kv[0], kv[1])
+	}
+	return args
+}
+
+// Add inserts a value for the given key.
+func (args Args) Add(key, value string) {
+	if _, ok := args.fields[key]; !ok {
+		args.fields[key] = map[string]bool{}
+	}
+	args.fields[key][value] = true
+}
+
+// Contains returns whether the key has been set at all.
+func (args Args) Contains(key string) bool {
+	values, ok := args.fields[key]
+	return ok && len(values) > 0
+}
+
+// Get returns the list of values set for key, in no particular order.
+func (args Args) Get(key string) []string {
+	values := args.fields[key]
+	result := make([]string, 0, len(values))
+	for v := range values {
+		result = append(result, v)
+	}
+	return result
+}
+
+// ExactMatch returns true if key is unset (nothing to filter on) or if value
+// is one of the values set for key.
+func (args Args) ExactMatch(key, value string) bool {
+	values, ok := args.fields[key]
+	if !ok || len(values) == 0 {
+		return true
+	}
+	return values[value]
+}
+
+// UniqueExactMatch is like ExactMatch but returns false when the key is
+// unset, useful when the caller needs to know whether the filter applies.
+func (args Args) UniqueExactMatch(key, value string) bool {
+	values, ok := args.fields[key]
+	if !ok || len(values) == 0 {
+		return false
+	}
+	return values[value]
+}
+
+// MatchKVList matches source, a set of key/value pairs (e.g. image labels),
+// against the values registered for key. Each registered value may either be
+// a bare key ("foo") which matches regardless of source's value, or a
+// "key=value" pair which requires an exact match. source must satisfy every
+// registered value for the match to succeed.
+func (args Args) MatchKVList(key string, source map[string]string) bool {
+	values, ok := args.fields[key]
+	if !ok || len(values) == 0 {
+		return true
+	}
+
+	for value := range values {
+		kv := strings.SplitN(value, "=", 2)
+		sourceVal, ok := source[kv[0]]
+		if !ok {
+			return false
+		}
+		if len(kv) == 2 && kv[1] != sourceVal {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate returns an error if args contains a key not present in accepted.
+func (args Args) Validate(accepted map[string]bool) error {
+	for key := range args.fields {
+		if !accepted[key] {
+			return fmt.Errorf("invalid filter %q", key)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of distinct keys set.
+func (args Args) Len() int {
+	return len(args.fields)
+}
@@ -0,0 +1,78 @@
+package filters
+
+import "testing"
+
+func TestArgsAddGetContains(t *testing.T) {
+	args := NewArgs()
+	if args.Contains("dangling") {
+		t.Fatalf("expected empty Args to not contain dangling")
+	}
+
+	args.Add("dangling", "true")
+	if !args.Contains("dangling") {
+		t.Fatalf("expected Args to contain dangling after Add")
+	}
+
+	values := args.Get("dangling")
+	if len(values) != 1 || values[0] != "true" {
+		t.Fatalf("got %v, want [true]", values)
+	}
+}
+
+func TestArgsExactMatch(t *testing.T) {
+	args := NewArgs()
+	if !args.ExactMatch("dangling", "true") {
+		t.Fatalf("ExactMatch with no filter set should always match")
+	}
+
+	args.Add("dangling", "true")
+	if !args.ExactMatch("dangling", "true") {
+		t.Fatalf("expected ExactMatch(dangling, true) to match")
+	}
+	if args.ExactMatch("dangling", "false") {
+		t.Fatalf("expected ExactMatch(dangling, false) to not match")
+	}
+}
+
+func TestArgsMatchKVList(t *testing.T) {
+	args := NewArgs()
+	labels := map[string]string{"env": "prod", "team": "infra"}
+
+	if !args.MatchKVList("label", labels) {
+		t.Fatalf("no label filter set should always match")
+	}
+
+	args.Add("label", "env=prod")
+	if !args.MatchKVList("label", labels) {
+		t.Fatalf("expected label=env=prod to match %v", labels)
+	}
+
+	args2 := NewArgs("label=env=staging")
+	if args2.MatchKVList("label", labels) {
+		t.Fatalf("expected label=env=staging to not match %v", labels)
+	}
+
+	args3 := NewArgs("label=team")
+	if !args3.MatchKVList("label", labels) {
+		t.Fatalf("expected bare key filter label=team to match %v", labels)
+	}
+
+	args4 := NewArgs("label=missing")
+	if args4.MatchKVList("label", labels) {
+		t.Fatalf("expected label=missing to not match %v", labels)
+	}
+}
+
+func TestArgsValidate(t *testing.T) {
+	accepted := map[string]bool{"dangling": true, "label": true}
+
+	args := NewArgs("dangling=true", "label=env=prod")
+	if err := args.Validate(accepted); err != nil {
+		t.Fatalf("unexpected error validating accepted filters: %v", err)
+	}
+
+	bad := NewArgs("bogus=true")
+	if err := bad.Validate(accepted); err == nil {
+		t.Fatalf("expected error validating an unknown filter key")
+	}
+}
@@ -0,0 +1,205 @@
+// Package retry provides a small exponential-backoff retry helper and the
+// error classification needed to decide whether an operation against a
+// registry is worth retrying at all.
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy configures the backoff schedule used by Do.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first
+	// one. A value <= 1 means "no retries".
+	MaxAttempts int
+
+	// InitialDelay is the delay before the first retry.
+	InitialDelay time.Duration
+
+	// Factor multiplies the delay after every attempt.
+	Factor float64
+
+	// MaxDelay caps the computed delay, regardless of Factor.
+	MaxDelay time.Duration
+
+	// Jitter adds up to +/-25% random variance to each computed delay so
+	// concurrent retries against the same registry don't thunder in
+	// lockstep.
+	Jitter bool
+}
+
+// DefaultPolicy is a conservative policy suitable for registry pulls: five
+// attempts, starting at one second and backing off up to thirty seconds.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:  5,
+		InitialDelay: time.Second,
+		Factor:       2,
+		MaxDelay:     30 * time.Second,
+		Jitter:       true,
+	}
+}
+
+// Do runs fn, retrying according to policy as long as fn's error is
+// classified as retryable. It honors ctx cancellation between attempts and
+// returns the last error encountered if every attempt fails.
+//
+// onRetry, if non-nil, is called before each retry with the attempt number
+// (starting at 1 for the first retry) and the error that triggered it, so
+// callers can surface progress (e.g. a jsonstream "retrying" event).
+func Do(ctx context.Context, policy Policy, retryable func(error) bool, onRetry func(attempt int, err error), fn func(ctx context.Context) error) error {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+
+	delay := policy.InitialDelay
+	var err error
+
+	for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+		err = fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if attempt == policy.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		wait := delay
+		if d := retryAfter(err); d > 0 {
+			wait = d
+		} else if policy.Jitter {
+			wait = jitter(wait)
+		}
+
+		if onRetry != nil {
+			onRetry(attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		delay = time.Duration(float64(delay) * policy.Factor)
+		if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+	return err
+}
+
+func jitter(d time.Duration) time.Duration {
+	// +/-25%
+	delta := float64(d) * 0.25
+	return d + time.Duration((rand.Float64()*2-1)*delta)
+}
+
+// httpStatusError is the minimal shape IsTransient/retryAfter need from an
+// HTTP-derived error; registry clients in this codebase wrap their errors
+// with at least this much context.
+type httpStatusError interface {
+	error
+	StatusCode() int
+}
+
+type retryAfterError interface {
+	error
+	RetryAfter() time.Duration
+}
+
+// statusCodePattern matches a bare 3-digit HTTP status code surrounded by
+// word boundaries, e.g. the "503" in containerd's remotes/docker resolver
+// error ("unexpected status code <url>: 503 Service Unavailable") or in
+// daemon/mgr's registry search errors ("...failed with status 429"). Neither
+// of those error types implements httpStatusError, so this is the only way
+// IsTransient can actually see the status code that triggered the error.
+var statusCodePattern = regexp.MustCompile(`\b([1-5][0-9]{2})\b`)
+
+// IsTransient classifies errors worth retrying: network-level errors, HTTP
+// 5xx/429 responses (recognized either through httpStatusError or by
+// pattern-matching the status code out of the error text), and containerd
+// content-store partial-blob errors (which surface as plain I/O errors from
+// an interrupted transfer).
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+
+	if se, ok := err.(httpStatusError); ok {
+		return isTransientStatus(se.StatusCode())
+	}
+
+	msg := err.Error()
+	for _, m := range statusCodePattern.FindAllStringSubmatch(msg, -1) {
+		code, err := strconv.Atoi(m[1])
+		if err == nil && isTransientStatus(code) {
+			return true
+		}
+	}
+
+	for _, marker := range []string{
+		"connection reset",
+		"connection refused",
+		"unexpected EOF",
+		"i/o timeout",
+		"broken pipe",
+		"blob: partial",
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransientStatus reports whether an HTTP status code is worth retrying:
+// 429 Too Many Requests, or any 5xx server error.
+func isTransientStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// retryAfterPattern matches a "Retry-After: <value>" header echoed into an
+// error's message, the same way real registry client errors commonly fold
+// response headers into their Error() text.
+var retryAfterPattern = regexp.MustCompile(`(?i)retry-after:\s*(\S+)`)
+
+// retryAfter extracts a server-provided Retry-After delay, if any, first
+// from a typed retryAfterError and otherwise by parsing it out of the error
+// text via ParseRetryAfter.
+func retryAfter(err error) time.Duration {
+	if ra, ok := err.(retryAfterError); ok {
+		return ra.RetryAfter()
+	}
+	if m := retryAfterPattern.FindStringSubmatch(err.Error()); m != nil {
+		return ParseRetryAfter(m[1])
+	}
+	return 0
+}
+
+// ParseRetryAfter parses the value of an HTTP Retry-After header, which is
+// either a number of seconds or an HTTP-date.
+func ParseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
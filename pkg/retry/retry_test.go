@@ -0,0 +1,113 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"connection reset", errors.New("read tcp: connection reset by peer"), true},
+		{"unexpected EOF", errors.New("unexpected EOF"), true},
+		{"containerd-style 503", errors.New("unexpected status code https://registry.example.com/v2/busybox/manifests/latest: 503 Service Unavailable"), true},
+		{"containerd-style 429", errors.New("unexpected status code https://registry.example.com/v2/busybox/manifests/latest: 429 Too Many Requests"), true},
+		{"daemon/mgr search error", errors.New("registry search request to https://registry.example.com/v1/search failed with status 500"), true},
+		{"404 not found", errors.New("unexpected status code https://registry.example.com/v2/busybox/manifests/latest: 404 Not Found"), false},
+		{"401 unauthorized", errors.New("registry search request to https://registry.example.com/v1/search failed with status 401"), false},
+		{"unrelated error", errors.New("invalid reference format"), false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsTransient(tc.err); got != tc.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string   { return "status error" }
+func (e *statusError) StatusCode() int { return e.status }
+
+func TestIsTransientHTTPStatusError(t *testing.T) {
+	if !IsTransient(&statusError{status: 503}) {
+		t.Fatalf("expected 503 httpStatusError to be transient")
+	}
+	if !IsTransient(&statusError{status: 429}) {
+		t.Fatalf("expected 429 httpStatusError to be transient")
+	}
+	if IsTransient(&statusError{status: 404}) {
+		t.Fatalf("expected 404 httpStatusError to not be transient")
+	}
+}
+
+func TestRetryAfterFromErrorText(t *testing.T) {
+	err := errors.New("too many requests, Retry-After: 2")
+	if got := retryAfter(err); got != 2*time.Second {
+		t.Fatalf("retryAfter(%v) = %v, want 2s", err, got)
+	}
+
+	if got := retryAfter(errors.New("no retry-after here")); got != 0 {
+		t.Fatalf("expected 0 when no Retry-After is present, got %v", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := ParseRetryAfter("120"); got != 120*time.Second {
+		t.Fatalf("ParseRetryAfter(120) = %v, want 120s", got)
+	}
+	if got := ParseRetryAfter(""); got != 0 {
+		t.Fatalf("ParseRetryAfter(\"\") = %v, want 0", got)
+	}
+	if got := ParseRetryAfter("not-a-date"); got != 0 {
+		t.Fatalf("ParseRetryAfter(garbage) = %v, want 0", got)
+	}
+}
+
+func TestDoRetriesTransientErrors(t *testing.T) {
+	attempts := 0
+	transient := errors.New("unexpected status code https://registry.example.com/v2/busybox/manifests/latest: 503 Service Unavailable")
+
+	err := Do(context.Background(), Policy{MaxAttempts: 3, InitialDelay: time.Millisecond}, IsTransient, nil,
+		func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return transient
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("expected Do to eventually succeed, got %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestDoStopsOnNonTransientError(t *testing.T) {
+	attempts := 0
+	permanent := errors.New("invalid reference format")
+
+	err := Do(context.Background(), Policy{MaxAttempts: 3, InitialDelay: time.Millisecond}, IsTransient, nil,
+		func(ctx context.Context) error {
+			attempts++
+			return permanent
+		})
+	if err != permanent {
+		t.Fatalf("expected Do to return the permanent error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected exactly 1 attempt for a non-transient error, got %d", attempts)
+	}
+}